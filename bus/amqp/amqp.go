@@ -0,0 +1,98 @@
+// Package amqp implements popple/bus on top of a RabbitMQ topic
+// exchange.
+package amqp
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/connorkuehl/popple/bus"
+	"github.com/connorkuehl/popple/event"
+)
+
+const exchange = "popple_topic"
+
+// Bus implements bus.Publisher, bus.Subscriber, and bus.GroupSubscriber
+// against an AMQP channel.
+type Bus struct {
+	ch *amqp.Channel
+}
+
+// New declares the popple_topic exchange on ch and returns a Bus that
+// publishes and subscribes through it.
+func New(ch *amqp.Channel) (*Bus, error) {
+	err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bus{ch: ch}, nil
+}
+
+func (b *Bus) Publish(subject string, evt event.Event) error {
+	payload, err := bus.Encode(evt)
+	if err != nil {
+		return err
+	}
+
+	return b.ch.PublishWithContext(
+		context.Background(),
+		exchange,
+		subject,
+		false,
+		false,
+		amqp.Publishing{Body: payload},
+	)
+}
+
+// Subscribe gives the caller its own exclusive, auto-delete queue bound
+// to subject, so every subscriber to subject receives every Event
+// published under it.
+func (b *Bus) Subscribe(subject string) (<-chan event.Event, error) {
+	qu, err := b.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.consume(qu.Name, subject)
+}
+
+// SubscribeGroup joins group as a competing consumer on subject: every
+// member of group shares one durable, non-exclusive queue, so each
+// Event published under subject is delivered to exactly one member
+// instead of to all of them.
+func (b *Bus) SubscribeGroup(subject, group string) (<-chan event.Event, error) {
+	qu, err := b.ch.QueueDeclare(group+"."+subject, true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.consume(qu.Name, subject)
+}
+
+func (b *Bus) consume(queue, subject string) (<-chan event.Event, error) {
+	err := b.ch.QueueBind(queue, subject, exchange, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := b.ch.Consume(queue, "", true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan event.Event)
+	go func() {
+		defer close(events)
+		for d := range deliveries {
+			evt, err := bus.Decode(d.Body)
+			if err != nil {
+				continue
+			}
+			events <- evt
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,65 @@
+// Package bus abstracts the message transport between Popple's chat
+// frontends and its karma worker. Concrete transports live in
+// popple/bus/amqp, popple/bus/nats, and popple/bus/inproc. popplebot
+// and popplesvc choose theirs at startup via the POPPLE_BUS environment
+// variable ("amqp" or "nats"); popple/bus/connect rejects "inproc", so
+// an inproc Bus is only used when constructed directly in code.
+package bus
+
+import (
+	"encoding/json"
+
+	"github.com/connorkuehl/popple/event"
+)
+
+// Publisher sends an Event under subject.
+type Publisher interface {
+	Publish(subject string, evt event.Event) error
+}
+
+// Subscriber delivers Events published under subject to every
+// subscriber, the way a broadcast would. The returned channel is
+// closed when the subscription ends.
+type Subscriber interface {
+	Subscribe(subject string) (<-chan event.Event, error)
+}
+
+// GroupSubscriber delivers Events published under subject to exactly
+// one member of group, the way a competing-consumer work queue would,
+// instead of broadcasting to every subscriber the way Subscriber does.
+// Use it when multiple replicas of the same service subscribe to the
+// same subject and a request must be handled by exactly one of them.
+type GroupSubscriber interface {
+	SubscribeGroup(subject, group string) (<-chan event.Event, error)
+}
+
+// Encode serializes evt for transport.
+func Encode(evt event.Event) ([]byte, error) {
+	return json.Marshal(evt)
+}
+
+// Decode deserializes a transported payload back into an Event.
+func Decode(data []byte) (event.Event, error) {
+	var evt event.Event
+	err := json.Unmarshal(data, &evt)
+	return evt, err
+}
+
+// Subjects that requests are published under. AMQP transports translate
+// the dots into topic exchange routing keys; NATS uses them as subjects
+// directly.
+//
+// Replies are not published under a shared subject: a requester
+// generates its own exclusive subject per process (see
+// NewReplySubject) so that, with multiple bot replicas running, a
+// reply reaches only the process that asked for it.
+const (
+	RequestBumpKarma        = "popple.request.bumpkarma"
+	RequestCheckKarma       = "popple.request.checkkarma"
+	RequestCheckLeaderboard = "popple.request.checkleaderboard"
+	RequestCheckLoserboard  = "popple.request.checkloserboard"
+	RequestChangeAnnounce   = "popple.request.changeannounce"
+	RequestBan              = "popple.request.ban"
+	RequestUnban            = "popple.request.unban"
+	RequestListBans         = "popple.request.listbans"
+)
@@ -0,0 +1,76 @@
+// Package connect picks and dials the popple/bus transport named by the
+// POPPLE_BUS environment variable, so cmd/popplebot and cmd/popplesvc
+// don't each have to duplicate the selection logic.
+package connect
+
+import (
+	"fmt"
+	"os"
+
+	amqpgo "github.com/rabbitmq/amqp091-go"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/connorkuehl/popple/bus"
+	busamqp "github.com/connorkuehl/popple/bus/amqp"
+	busnats "github.com/connorkuehl/popple/bus/nats"
+)
+
+// Transport is a bus that can publish, broadcast-subscribe, and join a
+// competing-consumer group.
+type Transport interface {
+	bus.Publisher
+	bus.Subscriber
+	bus.GroupSubscriber
+}
+
+// Dial selects and connects to the transport named by POPPLE_BUS
+// ("amqp" or "nats"; defaults to "amqp"), reading whatever further
+// environment variables that transport needs. The returned closer
+// should be called on shutdown; it is nil for transports with nothing
+// to close.
+//
+// bus/inproc is deliberately not one of the choices here: it's a Go
+// channel shared in memory, and Dial is called independently by each
+// binary (popplebot, popplesvc), so every call would get its own
+// disconnected inproc.Bus that never sees the other process's
+// publishes. inproc only makes sense when a single process constructs
+// one inproc.Bus and hands it directly to both the chat frontend and
+// the karma worker it's running in-process; there is no such process
+// in this tree yet.
+func Dial() (t Transport, closer func() error, err error) {
+	switch os.Getenv("POPPLE_BUS") {
+	case "nats":
+		conn, err := natsgo.Connect(os.Getenv("POPPLE_NATS_URL"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return busnats.New(conn), func() error { conn.Close(); return nil }, nil
+	case "inproc":
+		return nil, nil, fmt.Errorf("connect: inproc transport is not usable via Dial; popplebot and popplesvc are separate processes, so each Dial call would get its own disconnected bus")
+	case "", "amqp":
+		dsn := fmt.Sprintf(
+			"amqp://%s:%s@%s:%s",
+			os.Getenv("POPPLE_AMQP_USER"),
+			os.Getenv("POPPLE_AMQP_PASS"),
+			os.Getenv("POPPLE_AMQP_HOST"),
+			os.Getenv("POPPLE_AMQP_PORT"),
+		)
+		conn, err := amqpgo.Dial(dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch, err := conn.Channel()
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		t, err := busamqp.New(ch)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		return t, func() error { ch.Close(); return conn.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("connect: unknown POPPLE_BUS transport %q", os.Getenv("POPPLE_BUS"))
+	}
+}
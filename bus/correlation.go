@@ -0,0 +1,80 @@
+package bus
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewCorrelationID returns a random UUIDv4 suitable for
+// event.Correlation.ID.
+func NewCorrelationID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// NewReplySubject returns a subject unique to this process, suitable
+// for an exclusive, auto-delete reply subscription.
+func NewReplySubject() string {
+	return fmt.Sprintf("popple.reply.%s", NewCorrelationID())
+}
+
+// PendingRequests tracks correlation IDs a process is waiting on a
+// reply for, so a request whose reply never arrives (popplesvc
+// crashed, a message was dropped) can be timed out instead of leaving
+// the requester's user-facing command hanging forever. It is safe for
+// concurrent use.
+type PendingRequests struct {
+	mu      sync.Mutex
+	pending map[string]chan struct{}
+}
+
+// NewPendingRequests returns an empty PendingRequests.
+func NewPendingRequests() *PendingRequests {
+	return &PendingRequests{pending: make(map[string]chan struct{})}
+}
+
+// Await registers correlationID as outstanding. If Observe(correlationID)
+// is not called within timeout, onTimeout runs in its own goroutine.
+// Await does not block.
+func (p *PendingRequests) Await(correlationID string, timeout time.Duration, onTimeout func()) {
+	done := make(chan struct{})
+
+	p.mu.Lock()
+	p.pending[correlationID] = done
+	p.mu.Unlock()
+
+	go func() {
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			p.mu.Lock()
+			_, stillPending := p.pending[correlationID]
+			delete(p.pending, correlationID)
+			p.mu.Unlock()
+
+			if stillPending {
+				onTimeout()
+			}
+		}
+	}()
+}
+
+// Observe marks correlationID's reply as received, so its Await does
+// not time out. It is a no-op if correlationID isn't currently
+// pending, e.g. because it already timed out.
+func (p *PendingRequests) Observe(correlationID string) {
+	p.mu.Lock()
+	done, ok := p.pending[correlationID]
+	delete(p.pending, correlationID)
+	p.mu.Unlock()
+
+	if ok {
+		close(done)
+	}
+}
@@ -0,0 +1,46 @@
+package bus
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPendingRequestsAwaitTimesOut(t *testing.T) {
+	p := NewPendingRequests()
+
+	var fired int32
+	p.Await("correlation-1", 10*time.Millisecond, func() {
+		atomic.AddInt32(&fired, 1)
+	})
+
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&fired) != 1 {
+		t.Errorf("got fired=%d, want 1", fired)
+	}
+}
+
+func TestPendingRequestsObserveSuppressesTimeout(t *testing.T) {
+	p := NewPendingRequests()
+
+	var fired int32
+	p.Await("correlation-1", 10*time.Millisecond, func() {
+		atomic.AddInt32(&fired, 1)
+	})
+	p.Observe("correlation-1")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&fired) != 0 {
+		t.Errorf("got fired=%d, want 0 (Observe should have suppressed the timeout)", fired)
+	}
+}
+
+func TestPendingRequestsObserveIsNoOpWhenNotPending(t *testing.T) {
+	p := NewPendingRequests()
+
+	// Observing a correlation ID that was never Await-ed (or already
+	// timed out) must not panic or block.
+	p.Observe("never-awaited")
+}
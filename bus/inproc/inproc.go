@@ -0,0 +1,48 @@
+// Package inproc implements popple/bus with in-process Go channels, for
+// single-binary deployments (a chat frontend and the karma worker
+// sharing one process) and for tests.
+package inproc
+
+import (
+	"sync"
+
+	"github.com/connorkuehl/popple/event"
+)
+
+// Bus implements bus.Publisher and bus.Subscriber by fanning published
+// Events out to every channel currently subscribed to their subject.
+// It is safe for concurrent use.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string][]chan event.Event
+}
+
+// New returns an empty Bus.
+func New() *Bus {
+	return &Bus{subs: make(map[string][]chan event.Event)}
+}
+
+func (b *Bus) Publish(subject string, evt event.Event) error {
+	b.mu.Lock()
+	subs := append([]chan event.Event(nil), b.subs[subject]...)
+	b.mu.Unlock()
+
+	// Sends happen outside the lock: subs are unbuffered, so a slow or
+	// stuck subscriber would otherwise block every other Publish/
+	// Subscribe call on this Bus, not just this subject's.
+	for _, ch := range subs {
+		ch <- evt
+	}
+
+	return nil
+}
+
+func (b *Bus) Subscribe(subject string) (<-chan event.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan event.Event)
+	b.subs[subject] = append(b.subs[subject], ch)
+
+	return ch, nil
+}
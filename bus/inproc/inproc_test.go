@@ -0,0 +1,97 @@
+package inproc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/connorkuehl/popple/event"
+)
+
+func TestPublishDeliversToEverySubscriber(t *testing.T) {
+	b := New()
+
+	a, err := b.Subscribe("subject")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	c, err := b.Subscribe("subject")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	go func() {
+		if err := b.Publish("subject", event.Event{}); err != nil {
+			t.Errorf("Publish: %v", err)
+		}
+	}()
+
+	for _, ch := range []<-chan event.Event{a, c} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for subscriber to receive the published event")
+		}
+	}
+}
+
+func TestPublishIgnoresOtherSubjects(t *testing.T) {
+	b := New()
+
+	ch, err := b.Subscribe("subject")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := b.Publish("other", event.Event{}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case evt := <-ch:
+		t.Fatalf("got %v, want no event for an unrelated subject", evt)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// TestPublishDoesNotBlockOnAStuckSubscriber guards against the bug
+// Publish used to have: it held its lock for the whole fan-out send, so
+// one subscriber that never receives would wedge every other
+// subscriber and every other subject on the same Bus.
+func TestPublishDoesNotBlockOnAStuckSubscriber(t *testing.T) {
+	b := New()
+
+	stuck, err := b.Subscribe("stuck")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	_ = stuck // never received from, on purpose
+
+	go func() {
+		_ = b.Publish("stuck", event.Event{})
+	}()
+
+	unrelated, err := b.Subscribe("unrelated")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Publish("unrelated", event.Event{})
+	}()
+
+	select {
+	case <-unrelated:
+	case <-time.After(time.Second):
+		t.Fatal("Publish to an unrelated subject blocked on a stuck subscriber of another subject")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish did not return once its event was received")
+	}
+}
@@ -0,0 +1,84 @@
+// Package nats implements popple/bus on top of a NATS connection.
+package nats
+
+import (
+	"github.com/nats-io/nats.go"
+
+	"github.com/connorkuehl/popple/bus"
+	"github.com/connorkuehl/popple/event"
+)
+
+// Bus implements bus.Publisher, bus.Subscriber, and bus.GroupSubscriber
+// against a NATS connection.
+type Bus struct {
+	conn *nats.Conn
+
+	// closed is closed once, when conn's closed handler fires, so every
+	// Subscribe's event channel can be closed too.
+	closed chan struct{}
+}
+
+// New returns a Bus backed by conn. New installs its own closed handler
+// on conn via SetClosedHandler, replacing any handler already set.
+func New(conn *nats.Conn) *Bus {
+	b := &Bus{conn: conn, closed: make(chan struct{})}
+	conn.SetClosedHandler(func(*nats.Conn) {
+		close(b.closed)
+	})
+	return b
+}
+
+func (b *Bus) Publish(subject string, evt event.Event) error {
+	payload, err := bus.Encode(evt)
+	if err != nil {
+		return err
+	}
+
+	return b.conn.Publish(subject, payload)
+}
+
+// Subscribe delivers every Event published under subject to the
+// returned channel, the way NATS core pub-sub broadcasts to every
+// subscriber.
+func (b *Bus) Subscribe(subject string) (<-chan event.Event, error) {
+	return b.subscribe(func(events chan event.Event) (*nats.Subscription, error) {
+		return b.conn.Subscribe(subject, b.deliver(events))
+	})
+}
+
+// SubscribeGroup joins group as a competing consumer on subject, via
+// NATS's queue groups: subject is delivered to exactly one member of
+// group instead of to all of them.
+func (b *Bus) SubscribeGroup(subject, group string) (<-chan event.Event, error) {
+	return b.subscribe(func(events chan event.Event) (*nats.Subscription, error) {
+		return b.conn.QueueSubscribe(subject, group, b.deliver(events))
+	})
+}
+
+func (b *Bus) deliver(events chan event.Event) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		evt, err := bus.Decode(msg.Data)
+		if err != nil {
+			return
+		}
+		events <- evt
+	}
+}
+
+func (b *Bus) subscribe(open func(chan event.Event) (*nats.Subscription, error)) (<-chan event.Event, error) {
+	events := make(chan event.Event)
+
+	sub, err := open(events)
+	if err != nil {
+		close(events)
+		return nil, err
+	}
+
+	go func() {
+		<-b.closed
+		_ = sub.Unsubscribe()
+		close(events)
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,33 @@
+// Package discord adapts a discordgo.Session onto popple.Replier and
+// popple.Reactor so Popple's chat frontends can reply to Discord
+// without depending on discordgo directly.
+package discord
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Replier implements popple.Replier and popple.Reactor against a
+// discordgo.Session.
+type Replier struct {
+	Session *discordgo.Session
+}
+
+// New returns a Replier that sends through session.
+func New(session *discordgo.Session) *Replier {
+	return &Replier{Session: session}
+}
+
+func (r *Replier) Reply(ctx context.Context, channelID, text string) error {
+	if text == "" {
+		return nil
+	}
+	_, err := r.Session.ChannelMessageSend(channelID, text, discordgo.WithContext(ctx))
+	return err
+}
+
+func (r *Replier) React(ctx context.Context, channelID, messageID, emoji string) error {
+	return r.Session.MessageReactionAdd(channelID, messageID, emoji, discordgo.WithContext(ctx))
+}
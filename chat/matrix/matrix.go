@@ -0,0 +1,107 @@
+// Package matrix is a Matrix application-service chat frontend for
+// Popple. It registers with a homeserver as an appservice, translates
+// room messages into popple.Jobs, and reports back via room messages
+// and m.reaction events.
+package matrix
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"maunium.net/go/mautrix/appservice"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"github.com/connorkuehl/popple"
+)
+
+// Bridge runs the appservice and dispatches incoming room messages
+// through a popple.Mux.
+type Bridge struct {
+	AS *appservice.AppService
+}
+
+// New loads registration (an application-service registration YAML)
+// and returns a Bridge ready to Run.
+func New(homeserverURL, homeserverDomain, registrationPath string) (*Bridge, error) {
+	reg, err := appservice.LoadRegistration(registrationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	as, err := appservice.NewAppService(appservice.CreateOpts{
+		HomeserverDomain: homeserverDomain,
+		Registration:     reg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	as.HomeserverURL = homeserverURL
+
+	return &Bridge{AS: as}, nil
+}
+
+// Run starts the appservice HTTP listener and routes every room message
+// it receives through mux, publishing requests onto pub and replying
+// with replier/reactor. Run blocks until ctx is cancelled.
+func (b *Bridge) Run(ctx context.Context, mux *popple.Mux, pub popple.Publisher) error {
+	replier := &Replier{AS: b.AS}
+	botID := b.AS.BotMXID()
+
+	go b.AS.Start()
+	defer b.AS.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt := <-b.AS.Events:
+			if evt.Type != event.EventMessage || evt.Sender == botID {
+				continue
+			}
+
+			content, ok := evt.Content.Parsed.(*event.MessageEventContent)
+			if !ok {
+				continue
+			}
+
+			job := popple.Job{
+				GuildID:   evt.RoomID.String(),
+				ChannelID: evt.RoomID.String(),
+				MessageID: evt.ID.String(),
+				AuthorID:  evt.Sender.String(),
+				Content:   strings.TrimSpace(content.Body),
+			}
+
+			popple.Dispatch(ctx, mux, job, pub, replier, replier)
+		}
+	}
+}
+
+// Replier implements popple.Replier and popple.Reactor against a Matrix
+// appservice, translating channel/message IDs back into Matrix room and
+// event IDs.
+type Replier struct {
+	AS *appservice.AppService
+}
+
+// Reply and React below receive ctx to satisfy popple.Replier and
+// popple.Reactor, but IntentAPI.SendText/SendReaction don't take one:
+// the mautrix appservice client predates context.Context support.
+func (r *Replier) Reply(ctx context.Context, channelID, text string) error {
+	if text == "" {
+		return nil
+	}
+
+	_, err := r.AS.BotIntent().SendText(id.RoomID(channelID), text)
+	return err
+}
+
+func (r *Replier) React(ctx context.Context, channelID, messageID, emoji string) error {
+	_, err := r.AS.BotIntent().SendReaction(id.RoomID(channelID), id.EventID(messageID), emoji)
+	if err != nil {
+		log.Println("matrix: failed to react:", err)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// Package xmpp is an XMPP/MUC chat frontend for Popple. It joins one or
+// more multi-user chat rooms, translates groupchat messages into
+// popple.Jobs, and replies in-room. XMPP has no reaction primitive, so
+// Replier implements only popple.Replier and Dispatch is invoked with a
+// nil Reactor.
+package xmpp
+
+import (
+	"context"
+	"encoding/xml"
+	"log"
+	"strings"
+
+	"mellium.im/xmpp"
+	"mellium.im/xmpp/jid"
+	"mellium.im/xmpp/muc"
+	"mellium.im/xmpp/mux"
+	"mellium.im/xmpp/stanza"
+	"mellium.im/xmpp/xmlstream"
+
+	"github.com/connorkuehl/popple"
+)
+
+// Client joins MUC rooms and routes groupchat messages through a
+// popple.Mux.
+type Client struct {
+	Session *xmpp.Session
+	MUC     *muc.Client
+
+	// Nick is this bot's own nickname within joined rooms, used to
+	// ignore its own messages.
+	Nick string
+}
+
+// New wraps an already-authenticated xmpp.Session for use as a Popple
+// frontend.
+func New(session *xmpp.Session, nick string) *Client {
+	return &Client{Session: session, MUC: &muc.Client{}, Nick: nick}
+}
+
+// Join enters the MUC room at roomJID using c.Nick.
+func (c *Client) Join(ctx context.Context, roomJID jid.JID) (*muc.Channel, error) {
+	self, err := roomJID.WithResource(c.Nick)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.MUC.Join(ctx, c.Session, self)
+}
+
+// Run reads groupchat messages from room and routes them through mux,
+// publishing requests onto pub and replying in-room. Run blocks until
+// the session's connection is closed.
+func (c *Client) Run(ctx context.Context, room *muc.Channel, m *popple.Mux, pub popple.Publisher) error {
+	replier := &Replier{Session: c.Session, Room: room}
+
+	h := mux.New(stanza.NSClient, mux.MessageFunc(stanza.GroupChatMessage, xml.Name{Local: "message"}, func(msg stanza.Message, t xmlstream.TokenReadEncoder) error {
+		if msg.From.Resourcepart() == c.Nick {
+			return nil
+		}
+
+		var body struct {
+			XMLName xml.Name `xml:"body"`
+			Text    string   `xml:",chardata"`
+		}
+		if err := xml.NewTokenDecoder(t).Decode(&body); err != nil {
+			return nil
+		}
+
+		job := popple.Job{
+			GuildID:   room.Addr().Bare().String(),
+			ChannelID: room.Addr().Bare().String(),
+			MessageID: msg.ID,
+			AuthorID:  msg.From.String(),
+			Content:   strings.TrimSpace(body.Text),
+		}
+
+		popple.Dispatch(ctx, m, job, pub, replier, nil)
+		return nil
+	}))
+
+	return c.Session.Serve(h)
+}
+
+// Replier implements popple.Replier by sending groupchat messages back
+// into the room a request came from.
+type Replier struct {
+	Session *xmpp.Session
+	Room    *muc.Channel
+}
+
+func (r *Replier) Reply(ctx context.Context, channelID, text string) error {
+	if text == "" {
+		return nil
+	}
+
+	msg := stanza.Message{
+		To:   r.Room.Addr().Bare(),
+		Type: stanza.GroupChatMessage,
+	}
+
+	err := r.Session.Encode(ctx, msg.Wrap(xmlstream.Wrap(
+		xmlstream.Token(xml.CharData(text)),
+		xml.StartElement{Name: xml.Name{Local: "body"}},
+	)))
+	if err != nil {
+		log.Println("xmpp: failed to send reply:", err)
+	}
+	return err
+}
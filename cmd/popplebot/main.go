@@ -1,34 +1,32 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	amqp "github.com/rabbitmq/amqp091-go"
 
 	"github.com/connorkuehl/popple"
-	poperrs "github.com/connorkuehl/popple/errors"
+	"github.com/connorkuehl/popple/bus"
+	"github.com/connorkuehl/popple/bus/connect"
+	"github.com/connorkuehl/popple/chat/discord"
 	"github.com/connorkuehl/popple/event"
 )
 
-var (
-	token = os.Getenv("POPPLEBOT_DISCORD_TOKEN")
+var token = os.Getenv("POPPLEBOT_DISCORD_TOKEN")
 
-	amqpHost = os.Getenv("POPPLEBOT_AMQP_HOST")
-	amqpPort = os.Getenv("POPPLEBOT_AMQP_PORT")
-	amqpUser = os.Getenv("POPPLEBOT_AMQP_USER")
-	amqpPass = os.Getenv("POPPLEBOT_AMQP_PASS")
-)
+// requestTimeout is how long a published request is given to receive a
+// reply before the user is told it timed out. Requests aren't retried
+// on timeout: RequestBumpKarma isn't idempotent, so re-publishing it
+// could double-apply a karma change if the original request actually
+// did land, just slowly.
+const requestTimeout = 10 * time.Second
 
 var (
 	templateLevels = template.Must(template.New("levels").Parse(`{{ range $name, $karma := . }}{{ $name }} has {{ $karma }} karma. {{ end }}`))
@@ -37,96 +35,104 @@ var (
 {{ end }}`))
 )
 
-func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer cancel()
-
-	if err := run(ctx); err != nil {
-		log.Fatalln(err)
-	}
+// busPublisher adapts a bus.Publisher onto popple.Publisher, picking the
+// subject each request Event belongs on and stamping it with this
+// process's correlation metadata so only this process's reply
+// subscription receives the response.
+type busPublisher struct {
+	t            bus.Publisher
+	replySubject string
+	replier      popple.Replier
+	pending      *bus.PendingRequests
 }
 
-func run(ctx context.Context) error {
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%s", amqpUser, amqpPass, amqpHost, amqpPort))
-	if err != nil {
-		return err
+func (p *busPublisher) Publish(ctx context.Context, evt event.Event) error {
+	evt.Correlation = event.Correlation{
+		ID:           bus.NewCorrelationID(),
+		ReplySubject: p.replySubject,
 	}
-	defer conn.Close()
 
-	ch, err := conn.Channel()
-	if err != nil {
-		return err
-	}
-	defer ch.Close()
-
-	err = ch.ExchangeDeclare(
-		"popple_topic",
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+	if channelID, _ := replyTarget(evt); channelID != "" {
+		correlationID := evt.Correlation.ID
+		p.pending.Await(correlationID, requestTimeout, func() {
+			err := p.replier.Reply(context.Background(), channelID, "Sorry, that request timed out. Please try again.")
+			if err != nil {
+				log.Println("failed to report request timeout, correlation:", correlationID, "err:", err)
+			}
+		})
 	}
 
-	requestQueue, err := ch.QueueDeclare(
-		"requests",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+	switch {
+	case evt.RequestChangeAnnounce != nil:
+		return p.t.Publish(bus.RequestChangeAnnounce, evt)
+	case evt.RequestBumpKarma != nil:
+		return p.t.Publish(bus.RequestBumpKarma, evt)
+	case evt.RequestCheckKarma != nil:
+		return p.t.Publish(bus.RequestCheckKarma, evt)
+	case evt.RequestCheckLeaderboard != nil:
+		return p.t.Publish(bus.RequestCheckLeaderboard, evt)
+	case evt.RequestCheckLoserboard != nil:
+		return p.t.Publish(bus.RequestCheckLoserboard, evt)
+	case evt.RequestBan != nil:
+		return p.t.Publish(bus.RequestBan, evt)
+	case evt.RequestUnban != nil:
+		return p.t.Publish(bus.RequestUnban, evt)
+	case evt.RequestListBans != nil:
+		return p.t.Publish(bus.RequestListBans, evt)
+	default:
+		return nil
 	}
+}
 
-	queue, err := ch.QueueDeclare(
-		"",
-		false,
-		false,
-		true,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+// replyTarget returns the channel/message evt's eventual reply or
+// reaction should land on, so a request that times out can still tell
+// the user something went wrong.
+func replyTarget(evt event.Event) (channelID, messageID string) {
+	switch {
+	case evt.RequestChangeAnnounce != nil:
+		return evt.RequestChangeAnnounce.ReactTo.ChannelID, evt.RequestChangeAnnounce.ReactTo.MessageID
+	case evt.RequestBumpKarma != nil:
+		return evt.RequestBumpKarma.ReplyTo.ChannelID, ""
+	case evt.RequestCheckKarma != nil:
+		return evt.RequestCheckKarma.ReplyTo.ChannelID, ""
+	case evt.RequestCheckLeaderboard != nil:
+		return evt.RequestCheckLeaderboard.ReplyTo.ChannelID, ""
+	case evt.RequestCheckLoserboard != nil:
+		return evt.RequestCheckLoserboard.ReplyTo.ChannelID, ""
+	case evt.RequestBan != nil:
+		return evt.RequestBan.ReactTo.ChannelID, evt.RequestBan.ReactTo.MessageID
+	case evt.RequestUnban != nil:
+		return evt.RequestUnban.ReactTo.ChannelID, evt.RequestUnban.ReactTo.MessageID
+	case evt.RequestListBans != nil:
+		return evt.RequestListBans.ReplyTo.ChannelID, ""
+	default:
+		return "", ""
 	}
+}
 
-	err = ch.QueueBind(
-		queue.Name,
-		"checked.*",
-		"popple_topic",
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx); err != nil {
+		log.Fatalln(err)
 	}
+}
 
-	err = ch.QueueBind(
-		queue.Name,
-		"changed.*",
-		"popple_topic",
-		false,
-		nil,
-	)
+func run(ctx context.Context) error {
+	transport, closeBus, err := connect.Dial()
 	if err != nil {
 		return err
 	}
+	if closeBus != nil {
+		defer closeBus()
+	}
 
-	events, err := ch.Consume(
-		queue.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	// Every popplebot process owns one exclusive reply subject so that,
+	// with multiple replicas running for HA, a reply only ever reaches
+	// the process that published the request it answers.
+	replySubject := bus.NewReplySubject()
+	replies, err := transport.Subscribe(replySubject)
 	if err != nil {
 		return err
 	}
@@ -141,22 +147,27 @@ func run(ctx context.Context) error {
 		return err
 	}
 	defer session.Close()
-	log.Println("connected to Discord")
+	log.Println("connected to Discord, reply subject:", replySubject)
+
+	pending := bus.NewPendingRequests()
 
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go publisher(ctx, &wg, ch, requestQueue, session)
-	go consumer(ctx, &wg, events, session)
+	go publisher(ctx, &wg, transport, replySubject, session, pending)
+	go consumer(ctx, &wg, session, replies, pending)
 
 	wg.Wait()
 	return nil
 }
 
-func publisher(ctx context.Context, wg *sync.WaitGroup, ch *amqp.Channel, qu amqp.Queue, session *discordgo.Session) {
+func publisher(ctx context.Context, wg *sync.WaitGroup, transport bus.Publisher, replySubject string, session *discordgo.Session, pending *bus.PendingRequests) {
 	defer wg.Done()
 	defer log.Println("publisher has stopped")
 
 	mux := popple.NewMux("@" + session.State.User.Username)
+	replier := discord.New(session)
+	pub := &busPublisher{t: transport, replySubject: replySubject, replier: replier, pending: pending}
+
 	detachMessageCreate := session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
 		if s.State.User.ID == m.Author.ID {
 			return
@@ -167,195 +178,15 @@ func publisher(ctx context.Context, wg *sync.WaitGroup, ch *amqp.Channel, qu amq
 			return
 		}
 
-		message := strings.TrimSpace(m.ContentWithMentionsReplaced())
-		action, body := mux.Route(message)
-
-		switch action.(type) {
-		case popple.AnnounceHandler:
-			on, err := popple.ParseAnnounceArgs(body)
-			if errors.Is(err, poperrs.ErrMissingArgument) || errors.Is(err, poperrs.ErrInvalidArgument) {
-				err := s.MessageReactionAdd(m.ChannelID, m.ID, "❓")
-				if err != nil {
-					log.Println("failed to react to message:", err)
-				}
-				_, err = s.ChannelMessageSend(m.ChannelID, `Valid announce settings are: "on", "off", "yes", "no"`)
-				if err != nil {
-					log.Println("message send failed:", err)
-				}
-				return
-			}
-
-			var payload bytes.Buffer
-			err = json.NewEncoder(&payload).Encode(
-				event.Event{
-					RequestChangeAnnounce: &event.RequestChangeAnnounce{
-						ReactTo: event.ReactTo{
-							ChannelID: m.ChannelID,
-							MessageID: m.ID,
-						},
-						ServerID:   m.GuildID,
-						NoAnnounce: !on,
-					}})
-			if err != nil {
-				log.Println("failed to encode request.changeannounce:", err)
-				return
-			}
-
-			err = ch.PublishWithContext(
-				context.TODO(),
-				"",
-				qu.Name,
-				false,
-				false,
-				amqp.Publishing{
-					Body: payload.Bytes(),
-				},
-			)
-			if err != nil {
-				log.Println("failed to publish", payload, "err:", err)
-			}
-		case popple.BumpKarmaHandler:
-			increments, _ := popple.ParseBumpKarmaArgs(body)
-
-			var payload bytes.Buffer
-			err := json.NewEncoder(&payload).Encode(event.Event{
-				RequestBumpKarma: &event.RequestBumpKarma{
-					ReplyTo: event.ReplyTo{
-						ChannelID: m.ChannelID,
-					},
-					ServerID: m.GuildID,
-					Who:      increments,
-				}})
-			if err != nil {
-				log.Println("failed to encode request.bumpkarma:", err)
-				return
-			}
-
-			err = ch.PublishWithContext(
-				context.TODO(),
-				"",
-				qu.Name,
-				false,
-				false,
-				amqp.Publishing{
-					Body: payload.Bytes(),
-				},
-			)
-			if err != nil {
-				log.Println("failed to publish", payload, "err:", err)
-			}
-		case popple.KarmaHandler:
-			who, err := popple.ParseKarmaArgs(body)
-			if err != nil {
-				err = s.MessageReactionAdd(m.ChannelID, m.ID, "❓")
-				if err != nil {
-					log.Println("message reaction add failed:", err)
-					return
-				}
-			}
-
-			var payload bytes.Buffer
-			err = json.NewEncoder(&payload).Encode(event.Event{
-				RequestCheckKarma: &event.RequestCheckKarma{
-					ReplyTo: event.ReplyTo{
-						ChannelID: m.ChannelID,
-					},
-					ServerID: m.GuildID,
-					Who:      who,
-				}})
-			if err != nil {
-				log.Println("failed to encode request.checkkarma:", err)
-				return
-			}
-
-			err = ch.PublishWithContext(
-				context.TODO(),
-				"",
-				qu.Name,
-				false,
-				false,
-				amqp.Publishing{
-					Body: payload.Bytes(),
-				},
-			)
-			if err != nil {
-				log.Println("failed to publish", payload, "err:", err)
-			}
-		case popple.LeaderboardHandler:
-			limit, err := popple.ParseLeaderboardArgs(body)
-			if errors.Is(err, poperrs.ErrInvalidArgument) {
-				_, err := s.ChannelMessageSend(m.ChannelID, "The number of entries to list must be a positive non-zero integer")
-				if err != nil {
-					log.Println("message send failed:", err)
-				}
-				return
-			}
-
-			var payload bytes.Buffer
-			err = json.NewEncoder(&payload).Encode(event.Event{
-				RequestCheckLeaderboard: &event.RequestCheckLeaderboard{
-					ReplyTo: event.ReplyTo{
-						ChannelID: m.ChannelID,
-					},
-					ServerID: m.GuildID,
-					Limit:    limit,
-				}})
-			if err != nil {
-				log.Println("failed to encode request.checkleaderboard:", err)
-				return
-			}
-
-			err = ch.PublishWithContext(
-				context.TODO(),
-				"",
-				qu.Name,
-				false,
-				false,
-				amqp.Publishing{
-					Body: payload.Bytes(),
-				},
-			)
-			if err != nil {
-				log.Println("failed to publish", payload, "err:", err)
-			}
-		case popple.LoserboardHandler:
-			limit, err := popple.ParseLoserboardArgs(body)
-			if errors.Is(err, poperrs.ErrInvalidArgument) {
-				_, err := s.ChannelMessageSend(m.ChannelID, "The number of entries to list must be a positive non-zero integer")
-				if err != nil {
-					log.Println("message send failed:", err)
-				}
-				return
-			}
-
-			var payload bytes.Buffer
-			err = json.NewEncoder(&payload).Encode(event.Event{
-				RequestCheckLoserboard: &event.RequestCheckLoserboard{
-					ReplyTo: event.ReplyTo{
-						ChannelID: m.ChannelID,
-					},
-					ServerID: m.GuildID,
-					Limit:    limit,
-				}})
-			if err != nil {
-				log.Println("failed to encode request.checkloserboard:", err)
-				return
-			}
-
-			err = ch.PublishWithContext(
-				context.TODO(),
-				"",
-				qu.Name,
-				false,
-				false,
-				amqp.Publishing{
-					Body: payload.Bytes(),
-				},
-			)
-			if err != nil {
-				log.Println("failed to publish", payload, "err:", err)
-			}
+		job := popple.Job{
+			GuildID:   m.GuildID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			AuthorID:  m.Author.ID,
+			Content:   strings.TrimSpace(m.ContentWithMentionsReplaced()),
 		}
+
+		popple.Dispatch(ctx, mux, job, pub, replier, replier)
 	})
 	defer detachMessageCreate()
 	log.Println("publisher has started")
@@ -363,7 +194,7 @@ func publisher(ctx context.Context, wg *sync.WaitGroup, ch *amqp.Channel, qu amq
 	<-ctx.Done()
 }
 
-func consumer(ctx context.Context, wg *sync.WaitGroup, events <-chan amqp.Delivery, session *discordgo.Session) {
+func consumer(ctx context.Context, wg *sync.WaitGroup, session *discordgo.Session, replies <-chan event.Event, pending *bus.PendingRequests) {
 	defer wg.Done()
 	defer log.Println("consumer has stopped")
 	log.Println("consumer has started")
@@ -372,94 +203,78 @@ func consumer(ctx context.Context, wg *sync.WaitGroup, events <-chan amqp.Delive
 		select {
 		case <-ctx.Done():
 			return
-		case evt, ok := <-events:
+		case evt, ok := <-replies:
 			if !ok {
-				log.Println("consumer sees a closed events channel")
+				log.Println("consumer sees a closed reply subscription")
 				return
 			}
 
-			var actual event.Event
-			err := json.Unmarshal(evt.Body, &actual)
-			if err != nil {
-				log.Println("failed to deserialize event:", err)
-				continue
-			}
-
-			eventJSON, _ := json.Marshal(actual)
-			log.Println("got event", string(eventJSON))
+			pending.Observe(evt.Correlation.ID)
 
 			switch {
-			case actual.CheckedKarma != nil:
-				rsp := actual.CheckedKarma
-				var r strings.Builder
-				err := templateLevels.Execute(&r, rsp.Who)
-				if err != nil {
-					log.Println("failed to apply levels template:", err)
-					continue
-				}
-
-				_, err = session.ChannelMessageSend(rsp.ReplyTo.ChannelID, r.String())
+			case evt.CheckedKarma != nil:
+				sendLevels(ctx, session, evt.CheckedKarma.ReplyTo, evt.CheckedKarma.Who)
+			case evt.CheckedLeaderboard != nil:
+				sendBoard(ctx, session, evt.CheckedLeaderboard.ReplyTo, evt.CheckedLeaderboard.Board)
+			case evt.CheckedLoserboard != nil:
+				sendBoard(ctx, session, evt.CheckedLoserboard.ReplyTo, evt.CheckedLoserboard.Board)
+			case evt.ChangedAnnounce != nil:
+				rsp := evt.ChangedAnnounce
+				err := session.MessageReactionAdd(rsp.ReactTo.ChannelID, rsp.ReactTo.MessageID, "✅", discordgo.WithContext(ctx))
 				if err != nil {
-					log.Println("failed to send message:", err)
-					continue
-				}
-			case actual.CheckedLeaderboard != nil:
-				rsp := actual.CheckedLeaderboard
-				var r strings.Builder
-				err := templateBoard.Execute(&r, rsp.Board)
-				if err != nil {
-					log.Println("failed to apply board template:", err)
-					continue
-				}
-
-				_, err = session.ChannelMessageSend(rsp.ReplyTo.ChannelID, r.String())
-				if err != nil {
-					log.Println("failed to send message:", err)
-					continue
-				}
-			case actual.CheckedLoserboard != nil:
-				rsp := actual.CheckedLoserboard
-				var r strings.Builder
-				err := templateBoard.Execute(&r, rsp.Board)
-				if err != nil {
-					log.Println("failed to apply board template:", err)
-					continue
+					log.Println("failed to add reaction:", err)
 				}
-
-				_, err = session.ChannelMessageSend(rsp.ReplyTo.ChannelID, r.String())
-				if err != nil {
-					log.Println("failed to send message:", err)
-					continue
+			case evt.ChangedKarma != nil:
+				rsp := evt.ChangedKarma
+				if rsp.Announce {
+					sendLevels(ctx, session, rsp.ReplyTo, rsp.Who)
 				}
-			case actual.ChangedAnnounce != nil:
-				rsp := actual.ChangedAnnounce
-				err := session.MessageReactionAdd(rsp.ReactTo.ChannelID, rsp.ReactTo.MessageID, "✅")
-				if err != nil {
+			case evt.ChangedBan != nil:
+				rsp := evt.ChangedBan
+				if err := session.MessageReactionAdd(rsp.ReactTo.ChannelID, rsp.ReactTo.MessageID, "✅", discordgo.WithContext(ctx)); err != nil {
 					log.Println("failed to add reaction:", err)
-					continue
 				}
-			case actual.ChangedKarma != nil:
-				rsp := actual.ChangedKarma
-
-				if !rsp.Announce {
-					continue
+			case evt.ChangedUnban != nil:
+				rsp := evt.ChangedUnban
+				if err := session.MessageReactionAdd(rsp.ReactTo.ChannelID, rsp.ReactTo.MessageID, "✅", discordgo.WithContext(ctx)); err != nil {
+					log.Println("failed to add reaction:", err)
 				}
-
+			case evt.CheckedBans != nil:
+				rsp := evt.CheckedBans
 				var r strings.Builder
-				err := templateLevels.Execute(&r, rsp.Who)
-				if err != nil {
-					log.Println("failed to apply levels template:", err)
-					continue
+				for _, target := range rsp.Targets {
+					r.WriteString("* " + target + "\n")
 				}
-
-				_, err = session.ChannelMessageSend(rsp.ReplyTo.ChannelID, r.String())
-				if err != nil {
+				if _, err := session.ChannelMessageSend(rsp.ReplyTo.ChannelID, r.String(), discordgo.WithContext(ctx)); err != nil {
 					log.Println("failed to send message:", err)
-					continue
 				}
 			default:
-				log.Println("discarding unknown or unspecified event", evt)
+				log.Println("discarding unknown or unspecified event, correlation:", evt.Correlation.ID)
 			}
 		}
 	}
 }
+
+func sendLevels(ctx context.Context, session *discordgo.Session, replyTo event.ReplyTo, who map[string]int) {
+	var r strings.Builder
+	if err := templateLevels.Execute(&r, who); err != nil {
+		log.Println("failed to apply levels template:", err)
+		return
+	}
+
+	if _, err := session.ChannelMessageSend(replyTo.ChannelID, r.String(), discordgo.WithContext(ctx)); err != nil {
+		log.Println("failed to send message:", err)
+	}
+}
+
+func sendBoard(ctx context.Context, session *discordgo.Session, replyTo event.ReplyTo, board []event.BoardEntry) {
+	var r strings.Builder
+	if err := templateBoard.Execute(&r, board); err != nil {
+		log.Println("failed to apply board template:", err)
+		return
+	}
+
+	if _, err := session.ChannelMessageSend(replyTo.ChannelID, r.String(), discordgo.WithContext(ctx)); err != nil {
+		log.Println("failed to send message:", err)
+	}
+}
@@ -0,0 +1,249 @@
+// Command popplesvc is the karma worker: it consumes requests published
+// by chat frontends like popplebot, runs them through the karma engine
+// in popple/core against a database-backed Store, and publishes the
+// results back onto the bus.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/connorkuehl/popple/bus"
+	"github.com/connorkuehl/popple/bus/connect"
+	"github.com/connorkuehl/popple/core"
+	"github.com/connorkuehl/popple/event"
+	popplegorm "github.com/connorkuehl/popple/storage/gorm"
+)
+
+// banRefreshInterval is how often each guild's ban cache is reloaded
+// from the store once it's being watched.
+const banRefreshInterval = time.Minute
+
+// requestGroup is the competing-consumer group every popplesvc replica
+// joins to subscribe to request subjects: a request is delivered to
+// exactly one replica in the group, so running more replicas scales
+// throughput instead of every replica applying the same request.
+const requestGroup = "popplesvc"
+
+var dsn = os.Getenv("POPPLESVC_DB_DSN")
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&popplegorm.Entity{}, &popplegorm.Config{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&popplegorm.Ban{})
+	if err != nil {
+		return err
+	}
+
+	store := popplegorm.New(db)
+	bans := core.NewBanCache(store)
+	watcher := &banWatcher{ctx: ctx, bans: bans}
+
+	transport, closeBus, err := connect.Dial()
+	if err != nil {
+		return err
+	}
+	if closeBus != nil {
+		defer closeBus()
+	}
+
+	requests, err := subscribeAllGroup(transport, requestGroup,
+		bus.RequestChangeAnnounce,
+		bus.RequestBumpKarma,
+		bus.RequestCheckKarma,
+		bus.RequestCheckLeaderboard,
+		bus.RequestCheckLoserboard,
+		bus.RequestBan,
+		bus.RequestUnban,
+		bus.RequestListBans,
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Println("popplesvc has started")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case req, ok := <-requests:
+			if !ok {
+				return nil
+			}
+			handleRequest(core.Context{Context: ctx, Store: store, Bans: bans}, watcher, transport, req)
+		}
+	}
+}
+
+// banWatcher starts a background refresh loop for each guild the first
+// time it sees one, so BanCache stays current without polling the store
+// on every request.
+type banWatcher struct {
+	ctx  context.Context
+	bans *core.BanCache
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+func (w *banWatcher) watch(guildID string) {
+	w.mu.Lock()
+	if w.watched == nil {
+		w.watched = make(map[string]bool)
+	}
+	if w.watched[guildID] {
+		w.mu.Unlock()
+		return
+	}
+	w.watched[guildID] = true
+	w.mu.Unlock()
+
+	_ = w.bans.Refresh(w.ctx, guildID)
+	go w.bans.RefreshEvery(w.ctx, w.ctx.Done(), guildID, banRefreshInterval)
+}
+
+// subscribeAllGroup joins group as a competing consumer on every
+// subject and merges the resulting channels into one, so running
+// several popplesvc replicas splits the request load between them
+// instead of every replica handling every request.
+func subscribeAllGroup(sub bus.GroupSubscriber, group string, subjects ...string) (<-chan event.Event, error) {
+	merged := make(chan event.Event)
+	for _, subject := range subjects {
+		events, err := sub.SubscribeGroup(subject, group)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for evt := range events {
+				merged <- evt
+			}
+		}()
+	}
+	return merged, nil
+}
+
+func handleRequest(ctx core.Context, watcher *banWatcher, pub bus.Publisher, req event.Event) {
+	switch {
+	case req.RequestChangeAnnounce != nil:
+		r := req.RequestChangeAnnounce
+		if err := core.SetAnnounce(ctx, r.ServerID, !r.NoAnnounce); err != nil {
+			log.Println("SetAnnounce failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{ChangedAnnounce: &event.ChangedAnnounce{ReactTo: r.ReactTo}})
+	case req.RequestBumpKarma != nil:
+		r := req.RequestBumpKarma
+		watcher.watch(r.ServerID)
+
+		if ctx.Bans.Banned(r.ServerID, r.AuthorID) {
+			return
+		}
+
+		who, announce, err := core.ApplyKarmaDeltas(ctx, r.ServerID, r.Who)
+		if err != nil {
+			log.Println("ApplyKarmaDeltas failed:", err)
+			return
+		}
+
+		reply(pub, req.Correlation, event.Event{ChangedKarma: &event.ChangedKarma{
+			ReplyTo:  r.ReplyTo,
+			Announce: announce,
+			Who:      who,
+		}})
+	case req.RequestCheckKarma != nil:
+		r := req.RequestCheckKarma
+		who, err := core.KarmaOf(ctx, r.ServerID, r.Who)
+		if err != nil {
+			log.Println("KarmaOf failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{CheckedKarma: &event.CheckedKarma{ReplyTo: r.ReplyTo, Who: who}})
+	case req.RequestCheckLeaderboard != nil:
+		r := req.RequestCheckLeaderboard
+		entities, err := ctx.Store.Leaderboard(ctx, r.ServerID, r.Limit)
+		if err != nil {
+			log.Println("Leaderboard failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{CheckedLeaderboard: &event.CheckedLeaderboard{ReplyTo: r.ReplyTo, Board: toBoard(entities)}})
+	case req.RequestCheckLoserboard != nil:
+		r := req.RequestCheckLoserboard
+		entities, err := ctx.Store.Loserboard(ctx, r.ServerID, r.Limit)
+		if err != nil {
+			log.Println("Loserboard failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{CheckedLoserboard: &event.CheckedLoserboard{ReplyTo: r.ReplyTo, Board: toBoard(entities)}})
+	case req.RequestBan != nil:
+		r := req.RequestBan
+		watcher.watch(r.ServerID)
+
+		if err := core.BanTarget(ctx, r.ServerID, r.Target, r.ExpiresAt); err != nil {
+			log.Println("BanTarget failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{ChangedBan: &event.ChangedBan{ReactTo: r.ReactTo}})
+	case req.RequestUnban != nil:
+		r := req.RequestUnban
+		watcher.watch(r.ServerID)
+
+		if err := core.UnbanTarget(ctx, r.ServerID, r.Target); err != nil {
+			log.Println("UnbanTarget failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{ChangedUnban: &event.ChangedUnban{ReactTo: r.ReactTo}})
+	case req.RequestListBans != nil:
+		r := req.RequestListBans
+		targets, err := core.ActiveBans(ctx, r.ServerID)
+		if err != nil {
+			log.Println("ActiveBans failed:", err)
+			return
+		}
+		reply(pub, req.Correlation, event.Event{CheckedBans: &event.CheckedBans{ReplyTo: r.ReplyTo, Targets: targets}})
+	default:
+		log.Println("discarding unknown or unspecified request")
+	}
+}
+
+func toBoard(entities []core.Entity) []event.BoardEntry {
+	board := make([]event.BoardEntry, 0, len(entities))
+	for _, e := range entities {
+		board = append(board, event.BoardEntry{Name: e.Name, Karma: e.Karma})
+	}
+	return board
+}
+
+// reply publishes evt onto the requester's own exclusive reply subject,
+// echoing its correlation ID so the requester can match it against the
+// request it sent.
+func reply(pub bus.Publisher, correlation event.Correlation, evt event.Event) {
+	evt.Correlation = correlation
+
+	if err := pub.Publish(correlation.ReplySubject, evt); err != nil {
+		log.Println("failed to publish reply, correlation:", correlation.ID, "err:", err)
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ban records that a name may not accrue karma, or that a user may not
+// issue karma commands, within a guild.
+type Ban struct {
+	GuildID string
+	Target  string
+
+	// ExpiresAt is nil for a ban that never expires.
+	ExpiresAt *time.Time
+}
+
+// Active reports whether the ban is still in effect at t.
+func (b Ban) Active(t time.Time) bool {
+	return b.ExpiresAt == nil || t.Before(*b.ExpiresAt)
+}
+
+// BanCache mirrors a Store's active bans in memory so ApplyKarmaDeltas doesn't
+// have to hit the database on every karma mutation. Call Refresh
+// periodically (e.g. off a time.Ticker) to pick up new or expired bans.
+type BanCache struct {
+	store Store
+
+	mu   sync.RWMutex
+	bans map[string]map[string]struct{} // guildID -> target -> banned
+}
+
+// NewBanCache returns an empty BanCache backed by store. Call Refresh at
+// least once before relying on Banned.
+func NewBanCache(store Store) *BanCache {
+	return &BanCache{store: store, bans: make(map[string]map[string]struct{})}
+}
+
+// Refresh reloads guildID's active bans from the store.
+func (c *BanCache) Refresh(ctx context.Context, guildID string) error {
+	bans, err := c.store.Bans(ctx, guildID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	active := make(map[string]struct{})
+	for _, b := range bans {
+		if b.Active(now) {
+			active[b.Target] = struct{}{}
+		}
+	}
+
+	c.mu.Lock()
+	c.bans[guildID] = active
+	c.mu.Unlock()
+
+	return nil
+}
+
+// RefreshEvery calls Refresh(guildID) on every tick of interval until
+// ctx is cancelled or stop is closed.
+func (c *BanCache) RefreshEvery(ctx context.Context, stop <-chan struct{}, guildID string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = c.Refresh(ctx, guildID)
+		}
+	}
+}
+
+// Banned reports whether target is currently banned within guildID.
+func (c *BanCache) Banned(guildID, target string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.bans[guildID][target]
+	return ok
+}
@@ -0,0 +1,197 @@
+// Package core implements Popple's karma engine against a Store,
+// independent of any particular database or chat protocol. core has no
+// notion of replying to a request: every function here reads or
+// mutates the Store and returns a result, leaving it to the caller
+// (popplesvc's request handler) to deliver that result back to
+// whichever chat frontend asked for it.
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Entity is a named thing that accrues karma within a guild.
+type Entity struct {
+	GuildID string
+	Name    string
+	Karma   int
+}
+
+// Config is a guild's Popple settings.
+type Config struct {
+	GuildID    string
+	NoAnnounce bool
+}
+
+// Store persists Entities and Configs. Implementations live under
+// popple/storage; popple/storage/memory provides an in-memory stub for
+// tests. Every method takes ctx so a slow query can be cancelled when
+// the caller (Discord, AMQP) disconnects.
+type Store interface {
+	// GetEntity returns the named entity within guildID, or an Entity
+	// with that GuildID and Name and zero Karma if it does not exist.
+	GetEntity(ctx context.Context, guildID, name string) (Entity, error)
+
+	// SaveEntity creates or updates an entity.
+	SaveEntity(ctx context.Context, e Entity) error
+
+	// DeleteEntity removes an entity, e.g. once its karma returns to zero.
+	DeleteEntity(ctx context.Context, e Entity) error
+
+	// Leaderboard returns up to limit entities within guildID, highest
+	// karma first.
+	Leaderboard(ctx context.Context, guildID string, limit int) ([]Entity, error)
+
+	// Loserboard returns up to limit entities within guildID, lowest
+	// karma first.
+	Loserboard(ctx context.Context, guildID string, limit int) ([]Entity, error)
+
+	// Config returns guildID's settings, or default settings
+	// (NoAnnounce false) if none have been saved yet.
+	Config(ctx context.Context, guildID string) (Config, error)
+
+	// SaveConfig creates or updates a guild's settings.
+	SaveConfig(ctx context.Context, cfg Config) error
+
+	// SaveBan creates or replaces a ban on target (an entity name or a
+	// user ID) within guildID.
+	SaveBan(ctx context.Context, b Ban) error
+
+	// DeleteBan lifts a ban.
+	DeleteBan(ctx context.Context, guildID, target string) error
+
+	// Bans returns every ban recorded for guildID, expired or not; the
+	// caller is responsible for checking Ban.Active.
+	Bans(ctx context.Context, guildID string) ([]Ban, error)
+}
+
+// Context carries the Store a single request should be served with,
+// plus the context.Context governing it; passing Context itself
+// wherever a context.Context is expected cancels the Store calls it
+// makes along with the request. Bans is optional; a nil Bans disables
+// ban enforcement.
+type Context struct {
+	context.Context
+
+	Store Store
+	Bans  *BanCache
+}
+
+// KarmaOf reports the current karma of each named subject in who.
+func KarmaOf(ctx Context, guildID string, who []string) (map[string]int, error) {
+	karma := make(map[string]int, len(who))
+	for _, name := range who {
+		entity, err := ctx.Store.GetEntity(ctx, guildID, name)
+		if err != nil {
+			return nil, err
+		}
+		karma[name] = entity.Karma
+	}
+
+	return karma, nil
+}
+
+// ApplyKarmaDeltas applies the net karma change in who to guildID's
+// entities, skipping any subject that is individually banned, and
+// returns the post-change karma of every subject that wasn't skipped
+// along with whether the guild wants the result announced.
+func ApplyKarmaDeltas(ctx Context, guildID string, who map[string]int) (map[string]int, bool, error) {
+	applied := make(map[string]int, len(who))
+	for subject, netKarma := range who {
+		if netKarma == 0 {
+			continue
+		}
+
+		if ctx.Bans != nil && ctx.Bans.Banned(guildID, subject) {
+			continue
+		}
+
+		entity, err := ctx.Store.GetEntity(ctx, guildID, subject)
+		if err != nil {
+			return nil, false, err
+		}
+		entity.GuildID = guildID
+		entity.Name = subject
+		entity.Karma += netKarma
+
+		if entity.Karma == 0 {
+			if err := ctx.Store.DeleteEntity(ctx, entity); err != nil {
+				return nil, false, err
+			}
+		} else if err := ctx.Store.SaveEntity(ctx, entity); err != nil {
+			return nil, false, err
+		}
+
+		applied[subject] = entity.Karma
+	}
+
+	if len(applied) == 0 {
+		return applied, false, nil
+	}
+
+	cfg, err := ctx.Store.Config(ctx, guildID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return applied, !cfg.NoAnnounce, nil
+}
+
+// SetAnnounce turns announcements on or off for guildID.
+func SetAnnounce(ctx Context, guildID string, on bool) error {
+	cfg, err := ctx.Store.Config(ctx, guildID)
+	if err != nil {
+		return err
+	}
+	cfg.GuildID = guildID
+	cfg.NoAnnounce = !on
+
+	return ctx.Store.SaveConfig(ctx, cfg)
+}
+
+// BanTarget bans target (an entity name or a user ID) within guildID
+// until expiresAt, or indefinitely if expiresAt is nil.
+func BanTarget(ctx Context, guildID, target string, expiresAt *time.Time) error {
+	err := ctx.Store.SaveBan(ctx, Ban{GuildID: guildID, Target: target, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bans != nil {
+		return ctx.Bans.Refresh(ctx, guildID)
+	}
+	return nil
+}
+
+// UnbanTarget lifts a ban on target within guildID.
+func UnbanTarget(ctx Context, guildID, target string) error {
+	err := ctx.Store.DeleteBan(ctx, guildID, target)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bans != nil {
+		return ctx.Bans.Refresh(ctx, guildID)
+	}
+	return nil
+}
+
+// ActiveBans returns the target of every currently active ban within
+// guildID.
+func ActiveBans(ctx Context, guildID string) ([]string, error) {
+	bans, err := ctx.Store.Bans(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	targets := make([]string, 0, len(bans))
+	for _, b := range bans {
+		if b.Active(now) {
+			targets = append(targets, b.Target)
+		}
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,103 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/connorkuehl/popple/core"
+	"github.com/connorkuehl/popple/storage/memory"
+)
+
+func newCtx(t *testing.T) core.Context {
+	t.Helper()
+	store := memory.New()
+	return core.Context{Context: context.Background(), Store: store, Bans: core.NewBanCache(store)}
+}
+
+func TestApplyKarmaDeltas(t *testing.T) {
+	ctx := newCtx(t)
+
+	t.Run("applies a net karma change per subject", func(t *testing.T) {
+		applied, announce, err := core.ApplyKarmaDeltas(ctx, "guild", map[string]int{"foo": 2, "bar": -1})
+		if err != nil {
+			t.Fatalf("ApplyKarmaDeltas: %v", err)
+		}
+		if !announce {
+			t.Error("got announce false, want true")
+		}
+		if applied["foo"] != 2 || applied["bar"] != -1 {
+			t.Errorf("got %v, want foo=2 bar=-1", applied)
+		}
+	})
+
+	t.Run("zero-delta subjects are skipped", func(t *testing.T) {
+		applied, _, err := core.ApplyKarmaDeltas(ctx, "guild", map[string]int{"baz": 0})
+		if err != nil {
+			t.Fatalf("ApplyKarmaDeltas: %v", err)
+		}
+		if len(applied) != 0 {
+			t.Errorf("got %v, want no applied subjects", applied)
+		}
+	})
+
+	t.Run("a karma-banned subject is skipped", func(t *testing.T) {
+		if err := core.BanTarget(ctx, "guild", "quux", nil); err != nil {
+			t.Fatalf("BanTarget: %v", err)
+		}
+
+		applied, _, err := core.ApplyKarmaDeltas(ctx, "guild", map[string]int{"quux": 1})
+		if err != nil {
+			t.Fatalf("ApplyKarmaDeltas: %v", err)
+		}
+		if _, ok := applied["quux"]; ok {
+			t.Errorf("got %v, want quux omitted", applied)
+		}
+	})
+}
+
+func TestKarmaOf(t *testing.T) {
+	ctx := newCtx(t)
+
+	if _, _, err := core.ApplyKarmaDeltas(ctx, "guild", map[string]int{"foo": 3}); err != nil {
+		t.Fatalf("ApplyKarmaDeltas: %v", err)
+	}
+
+	karma, err := core.KarmaOf(ctx, "guild", []string{"foo", "never-bumped"})
+	if err != nil {
+		t.Fatalf("KarmaOf: %v", err)
+	}
+	if karma["foo"] != 3 {
+		t.Errorf("got foo=%d, want 3", karma["foo"])
+	}
+	if karma["never-bumped"] != 0 {
+		t.Errorf("got never-bumped=%d, want 0", karma["never-bumped"])
+	}
+}
+
+func TestActiveBans(t *testing.T) {
+	ctx := newCtx(t)
+
+	if err := core.BanTarget(ctx, "guild", "troll", nil); err != nil {
+		t.Fatalf("BanTarget: %v", err)
+	}
+
+	targets, err := core.ActiveBans(ctx, "guild")
+	if err != nil {
+		t.Fatalf("ActiveBans: %v", err)
+	}
+	if len(targets) != 1 || targets[0] != "troll" {
+		t.Errorf("got %v, want [troll]", targets)
+	}
+
+	if err := core.UnbanTarget(ctx, "guild", "troll"); err != nil {
+		t.Fatalf("UnbanTarget: %v", err)
+	}
+
+	targets, err = core.ActiveBans(ctx, "guild")
+	if err != nil {
+		t.Fatalf("ActiveBans: %v", err)
+	}
+	if len(targets) != 0 {
+		t.Errorf("got %v, want no active bans", targets)
+	}
+}
@@ -0,0 +1,135 @@
+package popple
+
+import (
+	"context"
+	"errors"
+
+	poperrs "github.com/connorkuehl/popple/errors"
+	"github.com/connorkuehl/popple/event"
+)
+
+// Publisher sends a request Event onto the bus for popplesvc to act on.
+type Publisher interface {
+	Publish(ctx context.Context, evt event.Event) error
+}
+
+// Replier sends a plain text reply into a channel.
+type Replier interface {
+	Reply(ctx context.Context, channelID, text string) error
+}
+
+// Reactor attaches a reaction emoji to a chat message. Not every
+// frontend supports reactions (XMPP does not), so Dispatch tolerates a
+// nil Reactor.
+type Reactor interface {
+	React(ctx context.Context, channelID, messageID, emoji string) error
+}
+
+// Dispatch routes job through mux and publishes the resulting request
+// onto pub, using reply/react to report parse errors directly back to
+// the user. Every chat frontend's publisher drives its incoming
+// messages through Dispatch so the command handling only has to be
+// written once. ctx is typically the chat frontend's process-lifetime
+// context, so in-flight publishes and replies are cancelled on
+// shutdown.
+func Dispatch(ctx context.Context, mux *Mux, job Job, pub Publisher, reply Replier, react Reactor) {
+	action, body := mux.Route(job.Content)
+
+	switch action.(type) {
+	case AnnounceHandler:
+		on, err := ParseAnnounceArgs(body)
+		if errors.Is(err, poperrs.ErrMissingArgument) || errors.Is(err, poperrs.ErrInvalidArgument) {
+			if react != nil {
+				_ = react.React(ctx, job.ChannelID, job.MessageID, "❓")
+			}
+			_ = reply.Reply(ctx, job.ChannelID, `Valid announce settings are: "on", "off", "yes", "no"`)
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestChangeAnnounce: &event.RequestChangeAnnounce{
+			ReactTo:    event.ReactTo{ChannelID: job.ChannelID, MessageID: job.MessageID},
+			ServerID:   job.GuildID,
+			NoAnnounce: !on,
+		}})
+	case KarmaHandler:
+		who, err := ParseKarmaArgs(body)
+		if err != nil {
+			if react != nil {
+				_ = react.React(ctx, job.ChannelID, job.MessageID, "❓")
+			}
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestCheckKarma: &event.RequestCheckKarma{
+			ReplyTo:  event.ReplyTo{ChannelID: job.ChannelID},
+			ServerID: job.GuildID,
+			Who:      who,
+		}})
+	case LeaderboardHandler:
+		limit, err := ParseLeaderboardArgs(body)
+		if errors.Is(err, poperrs.ErrInvalidArgument) {
+			_ = reply.Reply(ctx, job.ChannelID, "The number of entries to list must be a positive non-zero integer")
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestCheckLeaderboard: &event.RequestCheckLeaderboard{
+			ReplyTo:  event.ReplyTo{ChannelID: job.ChannelID},
+			ServerID: job.GuildID,
+			Limit:    limit,
+		}})
+	case LoserboardHandler:
+		limit, err := ParseLoserboardArgs(body)
+		if errors.Is(err, poperrs.ErrInvalidArgument) {
+			_ = reply.Reply(ctx, job.ChannelID, "The number of entries to list must be a positive non-zero integer")
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestCheckLoserboard: &event.RequestCheckLoserboard{
+			ReplyTo:  event.ReplyTo{ChannelID: job.ChannelID},
+			ServerID: job.GuildID,
+			Limit:    limit,
+		}})
+	case BanHandler:
+		target, expiresAt, err := ParseBanArgs(body)
+		if err != nil {
+			if react != nil {
+				_ = react.React(ctx, job.ChannelID, job.MessageID, "❓")
+			}
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestBan: &event.RequestBan{
+			ReactTo:   event.ReactTo{ChannelID: job.ChannelID, MessageID: job.MessageID},
+			ServerID:  job.GuildID,
+			Target:    target,
+			ExpiresAt: expiresAt,
+		}})
+	case UnbanHandler:
+		target, err := ParseUnbanArgs(body)
+		if err != nil {
+			if react != nil {
+				_ = react.React(ctx, job.ChannelID, job.MessageID, "❓")
+			}
+			return
+		}
+
+		_ = pub.Publish(ctx, event.Event{RequestUnban: &event.RequestUnban{
+			ReactTo:  event.ReactTo{ChannelID: job.ChannelID, MessageID: job.MessageID},
+			ServerID: job.GuildID,
+			Target:   target,
+		}})
+	case BannedHandler:
+		_ = pub.Publish(ctx, event.Event{RequestListBans: &event.RequestListBans{
+			ReplyTo:  event.ReplyTo{ChannelID: job.ChannelID},
+			ServerID: job.GuildID,
+		}})
+	default: // BumpKarmaHandler
+		increments, _ := ParseBumpKarmaArgs(body)
+		_ = pub.Publish(ctx, event.Event{RequestBumpKarma: &event.RequestBumpKarma{
+			ReplyTo:  event.ReplyTo{ChannelID: job.ChannelID},
+			ServerID: job.GuildID,
+			AuthorID: job.AuthorID,
+			Who:      increments,
+		}})
+	}
+}
@@ -0,0 +1,15 @@
+// Package errors defines the sentinel errors shared across Popple's
+// command parsing and handler layers.
+package errors
+
+import "errors"
+
+var (
+	// ErrMissingArgument is returned when a command requires an argument
+	// that the caller did not supply.
+	ErrMissingArgument = errors.New("missing argument")
+
+	// ErrInvalidArgument is returned when a command's argument could not
+	// be parsed into the form the command expects.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
@@ -0,0 +1,165 @@
+// Package event defines the JSON envelope that flows between Popple's
+// chat frontends and its karma worker over the message bus.
+package event
+
+import "time"
+
+// ReplyTo identifies the channel a response should be delivered to.
+type ReplyTo struct {
+	ChannelID string
+}
+
+// ReactTo identifies the specific message a reaction response should be
+// attached to.
+type ReactTo struct {
+	ChannelID string
+	MessageID string
+}
+
+// Correlation carries RPC routing metadata alongside a request so its
+// reply reaches only the bot process that sent it. ID is a UUID the
+// requester generates per request; ReplySubject is the bus subject of
+// an exclusive, auto-delete reply subscription that process owns.
+// Worker replies echo the Correlation of the request they're answering.
+type Correlation struct {
+	ID           string
+	ReplySubject string
+}
+
+// Event is the envelope published onto the bus. Exactly one of the
+// Request*/Changed*/Checked* fields is populated per message; the rest
+// are left nil. Correlation is populated on requests and echoed back
+// unchanged on their replies.
+type Event struct {
+	Correlation Correlation `json:",omitempty"`
+
+	RequestChangeAnnounce   *RequestChangeAnnounce   `json:",omitempty"`
+	RequestBumpKarma        *RequestBumpKarma        `json:",omitempty"`
+	RequestCheckKarma       *RequestCheckKarma       `json:",omitempty"`
+	RequestCheckLeaderboard *RequestCheckLeaderboard `json:",omitempty"`
+	RequestCheckLoserboard  *RequestCheckLoserboard  `json:",omitempty"`
+	RequestBan              *RequestBan              `json:",omitempty"`
+	RequestUnban            *RequestUnban            `json:",omitempty"`
+	RequestListBans         *RequestListBans         `json:",omitempty"`
+
+	ChangedAnnounce    *ChangedAnnounce    `json:",omitempty"`
+	ChangedKarma       *ChangedKarma       `json:",omitempty"`
+	CheckedKarma       *CheckedKarma       `json:",omitempty"`
+	CheckedLeaderboard *CheckedLeaderboard `json:",omitempty"`
+	CheckedLoserboard  *CheckedLoserboard  `json:",omitempty"`
+	ChangedBan         *ChangedBan         `json:",omitempty"`
+	ChangedUnban       *ChangedUnban       `json:",omitempty"`
+	CheckedBans        *CheckedBans        `json:",omitempty"`
+}
+
+// RequestChangeAnnounce asks the worker to toggle announce mode for a guild.
+type RequestChangeAnnounce struct {
+	ReactTo    ReactTo
+	ServerID   string
+	NoAnnounce bool
+}
+
+// RequestBumpKarma asks the worker to apply karma increments. AuthorID
+// identifies whoever issued the command, so the worker can refuse it if
+// that user is banned.
+type RequestBumpKarma struct {
+	ReplyTo  ReplyTo
+	ServerID string
+	AuthorID string
+	Who      map[string]int
+}
+
+// RequestCheckKarma asks the worker to report the karma of one or more subjects.
+type RequestCheckKarma struct {
+	ReplyTo  ReplyTo
+	ServerID string
+	Who      []string
+}
+
+// RequestCheckLeaderboard asks the worker for the highest-karma entities.
+type RequestCheckLeaderboard struct {
+	ReplyTo  ReplyTo
+	ServerID string
+	Limit    int
+}
+
+// RequestCheckLoserboard asks the worker for the lowest-karma entities.
+type RequestCheckLoserboard struct {
+	ReplyTo  ReplyTo
+	ServerID string
+	Limit    int
+}
+
+// RequestBan asks the worker to ban target (an entity name or a user
+// ID) within ServerID, until ExpiresAt if set or indefinitely if nil.
+type RequestBan struct {
+	ReactTo   ReactTo
+	ServerID  string
+	Target    string
+	ExpiresAt *time.Time
+}
+
+// RequestUnban asks the worker to lift a ban on target within ServerID.
+type RequestUnban struct {
+	ReactTo  ReactTo
+	ServerID string
+	Target   string
+}
+
+// RequestListBans asks the worker for every active ban within ServerID.
+type RequestListBans struct {
+	ReplyTo  ReplyTo
+	ServerID string
+}
+
+// BoardEntry is one row of a leaderboard/loserboard response.
+type BoardEntry struct {
+	Name  string
+	Karma int
+}
+
+// ChangedAnnounce is the worker's reply to RequestChangeAnnounce.
+type ChangedAnnounce struct {
+	ReactTo ReactTo
+}
+
+// ChangedKarma is the worker's reply to RequestBumpKarma.
+type ChangedKarma struct {
+	ReplyTo  ReplyTo
+	Announce bool
+	Who      map[string]int
+}
+
+// CheckedKarma is the worker's reply to RequestCheckKarma.
+type CheckedKarma struct {
+	ReplyTo ReplyTo
+	Who     map[string]int
+}
+
+// CheckedLeaderboard is the worker's reply to RequestCheckLeaderboard.
+type CheckedLeaderboard struct {
+	ReplyTo ReplyTo
+	Board   []BoardEntry
+}
+
+// CheckedLoserboard is the worker's reply to RequestCheckLoserboard.
+type CheckedLoserboard struct {
+	ReplyTo ReplyTo
+	Board   []BoardEntry
+}
+
+// ChangedBan is the worker's reply to RequestBan.
+type ChangedBan struct {
+	ReactTo ReactTo
+}
+
+// ChangedUnban is the worker's reply to RequestUnban.
+type ChangedUnban struct {
+	ReactTo ReactTo
+}
+
+// CheckedBans is the worker's reply to RequestListBans.
+type CheckedBans struct {
+	ReplyTo ReplyTo
+	Targets []string
+}
@@ -0,0 +1,23 @@
+package popple
+
+// Job is a single command invocation received from a chat frontend,
+// abstracted away from the underlying protocol (Discord, Matrix, XMPP).
+type Job struct {
+	// GuildID scopes karma to a server/room. For Discord this is the
+	// guild ID; for Matrix, the room ID; for XMPP MUC, the room JID.
+	GuildID string
+
+	// ChannelID is where a reply should be sent.
+	ChannelID string
+
+	// MessageID is the message a reaction response should attach to.
+	MessageID string
+
+	// AuthorID identifies whoever sent the message, so bans on users
+	// (as opposed to entity names) can be enforced.
+	AuthorID string
+
+	// Content is the message text, with any mentions already resolved
+	// to display names.
+	Content string
+}
@@ -0,0 +1,99 @@
+package popple
+
+import "strings"
+
+// Handler identifies which command a routed message maps to. Dispatch,
+// which every chat frontend calls, type-switches on the concrete
+// Handler type to decide how to build the request it publishes.
+type Handler interface {
+	isHandler()
+}
+
+// AnnounceHandler routes to the announce on/off toggle.
+type AnnounceHandler struct{}
+
+// KarmaHandler routes to a karma lookup for one or more subjects.
+type KarmaHandler struct{}
+
+// LeaderboardHandler routes to the highest-karma entities.
+type LeaderboardHandler struct{}
+
+// LoserboardHandler routes to the lowest-karma entities.
+type LoserboardHandler struct{}
+
+// BumpKarmaHandler routes to a karma increment/decrement. It is the
+// default when no other command name matches.
+type BumpKarmaHandler struct{}
+
+// BanHandler routes to banning a name or user from accruing karma.
+type BanHandler struct{}
+
+// UnbanHandler routes to lifting a ban.
+type UnbanHandler struct{}
+
+// BannedHandler routes to listing active bans.
+type BannedHandler struct{}
+
+func (AnnounceHandler) isHandler()    {}
+func (KarmaHandler) isHandler()       {}
+func (LeaderboardHandler) isHandler() {}
+func (LoserboardHandler) isHandler()  {}
+func (BumpKarmaHandler) isHandler()   {}
+func (BanHandler) isHandler()         {}
+func (UnbanHandler) isHandler()       {}
+func (BannedHandler) isHandler()      {}
+
+// Mux decides which Handler a chat message is destined for based on a
+// configured command prefix, e.g. "@popple".
+type Mux struct {
+	prefix string
+}
+
+// NewMux returns a Mux that recognizes commands addressed to prefix.
+func NewMux(prefix string) *Mux {
+	return &Mux{prefix: prefix}
+}
+
+// Route matches message against m's prefix and command names, returning
+// the Handler to run and the remainder of message with the matched
+// prefix and command name stripped off.
+//
+// Route requires at least one whitespace character between the prefix
+// and the command name, but otherwise tolerates any amount of it;
+// anything else falls through to BumpKarmaHandler with message
+// returned unmodified.
+func (m *Mux) Route(message string) (Handler, string) {
+	if !strings.HasPrefix(message, m.prefix) {
+		return BumpKarmaHandler{}, message
+	}
+
+	rest := message[len(m.prefix):]
+	trimmed := strings.TrimLeft(rest, " \t")
+	if trimmed == rest {
+		return BumpKarmaHandler{}, message
+	}
+
+	cmd := parseCommand(trimmed)
+
+	var handler Handler
+	switch cmd.Name {
+	case "announce":
+		handler = AnnounceHandler{}
+	case "karma":
+		handler = KarmaHandler{}
+	case "top":
+		handler = LeaderboardHandler{}
+	case "bot":
+		handler = LoserboardHandler{}
+	case "ban":
+		handler = BanHandler{}
+	case "unban":
+		handler = UnbanHandler{}
+	case "banned":
+		handler = BannedHandler{}
+	default:
+		return BumpKarmaHandler{}, message
+	}
+
+	return handler, cmd.Rest
+}
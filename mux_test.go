@@ -45,8 +45,6 @@ func TestMux(t *testing.T) {
 	})
 
 	t.Run("it allows any amount of whitespace between the name and cmd", func(t *testing.T) {
-		t.Skip("https://github.com/connorkuehl/popple/issues/112")
-
 		tests := []struct {
 			in    string
 			check func(got interface{})
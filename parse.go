@@ -0,0 +1,149 @@
+package popple
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	poperrs "github.com/connorkuehl/popple/errors"
+)
+
+// ParseAnnounceArgs parses the body of an "announce" command into the
+// on/off setting it requests.
+func ParseAnnounceArgs(body string) (bool, error) {
+	body = strings.TrimSpace(body)
+
+	switch {
+	case body == "":
+		return false, poperrs.ErrMissingArgument
+	case strings.HasPrefix(body, "on") || strings.HasPrefix(body, "yes"):
+		return true, nil
+	case strings.HasPrefix(body, "off") || strings.HasPrefix(body, "no"):
+		return false, nil
+	default:
+		return false, poperrs.ErrInvalidArgument
+	}
+}
+
+// ParseBumpKarmaArgs parses the body of a default (bump karma) command
+// into a map of subject name to net karma change.
+func ParseBumpKarmaArgs(body string) (map[string]int, error) {
+	return MarshalSubjects(ParseSubjects(body)), nil
+}
+
+// ParseKarmaArgs parses the body of a "karma" command into the list of
+// subjects whose karma was requested. A subject name may be
+// double-quoted to admit whitespace, e.g. `"kernel panic"`.
+func ParseKarmaArgs(body string) ([]string, error) {
+	words := splitWords(body)
+	if len(words) == 0 {
+		return nil, poperrs.ErrMissingArgument
+	}
+
+	return words, nil
+}
+
+// ParseLeaderboardArgs parses the body of a "top" command into the
+// number of entries to return.
+func ParseLeaderboardArgs(body string) (int, error) {
+	return parseBoardLimit(body)
+}
+
+// ParseLoserboardArgs parses the body of a "bot" command into the
+// number of entries to return.
+func ParseLoserboardArgs(body string) (int, error) {
+	return parseBoardLimit(body)
+}
+
+// ParseBanArgs parses the body of a "ban" command into the target to
+// ban and an optional expiry. It accepts a bare "@user" mention (its
+// "@" is stripped, so it bans the same name ApplyKarmaDeltas would match), or
+// "name Foo" / "user 123456" / "ip 203.0.113.1", optionally followed by
+// a duration such as "24h" or "7d" after which the ban lifts itself.
+func ParseBanArgs(body string) (target string, expiresAt *time.Time, err error) {
+	fields := splitWords(body)
+	if len(fields) == 0 {
+		return "", nil, poperrs.ErrMissingArgument
+	}
+
+	switch fields[0] {
+	case "name", "user", "ip":
+		if len(fields) < 2 {
+			return "", nil, poperrs.ErrMissingArgument
+		}
+		target, fields = fields[1], fields[2:]
+	default:
+		target, fields = stripMention(fields[0]), fields[1:]
+	}
+
+	if len(fields) > 0 {
+		d, err := parseBanDuration(fields[0])
+		if err != nil {
+			return "", nil, poperrs.ErrInvalidArgument
+		}
+		expiry := time.Now().Add(d)
+		expiresAt = &expiry
+	}
+
+	return target, expiresAt, nil
+}
+
+// stripMention folds a bare "@user" mention into the name "user", the
+// same way MarshalSubjects does for karma subjects, so a ban on "@user"
+// matches the subject name ApplyKarmaDeltas checks rather than being stored
+// with its "@" still attached.
+func stripMention(s string) string {
+	if len(s) > 1 && s[0] == '@' {
+		return s[1:]
+	}
+	return s
+}
+
+// ParseUnbanArgs parses the body of an "unban" command into the target
+// whose ban should be lifted.
+func ParseUnbanArgs(body string) (string, error) {
+	fields := splitWords(body)
+	if len(fields) == 0 {
+		return "", poperrs.ErrMissingArgument
+	}
+
+	switch fields[0] {
+	case "name", "user", "ip":
+		if len(fields) < 2 {
+			return "", poperrs.ErrMissingArgument
+		}
+		return fields[1], nil
+	default:
+		return stripMention(fields[0]), nil
+	}
+}
+
+// parseBanDuration parses a Go duration string like "24h", plus the
+// day-suffixed shorthand "7d" that time.ParseDuration doesn't accept.
+func parseBanDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+func parseBoardLimit(body string) (int, error) {
+	const defaultLimit = 10
+
+	fields := splitWords(body)
+	if len(fields) == 0 {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(fields[0])
+	if err != nil || limit <= 0 {
+		return 0, poperrs.ErrInvalidArgument
+	}
+
+	return limit, nil
+}
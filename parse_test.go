@@ -0,0 +1,72 @@
+package popple
+
+import "testing"
+
+func TestParseBanArgs(t *testing.T) {
+	t.Run("a bare @mention bans the bare name", func(t *testing.T) {
+		target, _, err := ParseBanArgs("@troll")
+		if err != nil {
+			t.Fatalf("ParseBanArgs: %v", err)
+		}
+		if target != "troll" {
+			t.Errorf("got target %q, want %q", target, "troll")
+		}
+	})
+
+	t.Run("a quoted name with whitespace is kept as one target", func(t *testing.T) {
+		target, _, err := ParseBanArgs(`name "Sir Trolls-A-Lot"`)
+		if err != nil {
+			t.Fatalf("ParseBanArgs: %v", err)
+		}
+		if target != "Sir Trolls-A-Lot" {
+			t.Errorf("got target %q, want %q", target, "Sir Trolls-A-Lot")
+		}
+	})
+
+	t.Run("name Foo bans Foo verbatim", func(t *testing.T) {
+		target, _, err := ParseBanArgs("name Foo")
+		if err != nil {
+			t.Fatalf("ParseBanArgs: %v", err)
+		}
+		if target != "Foo" {
+			t.Errorf("got target %q, want %q", target, "Foo")
+		}
+	})
+
+	t.Run("a banned @mention matches the subject MarshalSubjects produces", func(t *testing.T) {
+		target, _, err := ParseBanArgs("@troll")
+		if err != nil {
+			t.Fatalf("ParseBanArgs: %v", err)
+		}
+
+		who := MarshalSubjects(ParseSubjects("@troll++"))
+		if _, ok := who[target]; !ok {
+			t.Errorf("ban target %q does not match karma subject %v", target, who)
+		}
+	})
+}
+
+func TestParseKarmaArgs(t *testing.T) {
+	t.Run("a quoted subject with whitespace is kept as one entry", func(t *testing.T) {
+		who, err := ParseKarmaArgs(`"kernel panic" foo`)
+		if err != nil {
+			t.Fatalf("ParseKarmaArgs: %v", err)
+		}
+		want := []string{"kernel panic", "foo"}
+		if len(who) != len(want) || who[0] != want[0] || who[1] != want[1] {
+			t.Errorf("got %v, want %v", who, want)
+		}
+	})
+}
+
+func TestParseUnbanArgs(t *testing.T) {
+	t.Run("a bare @mention unbans the bare name", func(t *testing.T) {
+		target, err := ParseUnbanArgs("@troll")
+		if err != nil {
+			t.Fatalf("ParseUnbanArgs: %v", err)
+		}
+		if target != "troll" {
+			t.Errorf("got target %q, want %q", target, "troll")
+		}
+	})
+}
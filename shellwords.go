@@ -0,0 +1,57 @@
+package popple
+
+import "strings"
+
+// Command is a single chat invocation parsed out of a message: Name is
+// the command word, and Rest is the raw, unparsed text following Name,
+// quotes intact, for handlers (like the ban/karma/board arg parsers) to
+// tokenize themselves with splitWords.
+type Command struct {
+	Name string
+	Rest string
+}
+
+// parseCommand splits message the way a shell would: any run of
+// whitespace separates words, and "double-quoted spans" are kept
+// together as a single word with the quotes stripped. message is
+// assumed to have no leading whitespace; the zero Command is returned
+// if it's empty.
+func parseCommand(message string) Command {
+	name, rest := splitWord(message)
+	return Command{Name: name, Rest: rest}
+}
+
+// splitWord pulls the first shell word off the front of s (quotes
+// stripped) and returns it along with everything after it, whitespace
+// intact.
+func splitWord(s string) (word, rest string) {
+	inQuotes := false
+
+	i := 0
+	for i < len(s) {
+		switch {
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (s[i] == ' ' || s[i] == '\t'):
+			return strings.ReplaceAll(s[:i], `"`, ""), s[i:]
+		}
+		i++
+	}
+
+	return strings.ReplaceAll(s, `"`, ""), ""
+}
+
+// splitWords tokenizes s into shell words: any run of whitespace
+// separates words, and "double-quoted spans" become a single word
+// with the quotes stripped.
+func splitWords(s string) []string {
+	var words []string
+
+	for s = strings.TrimLeft(s, " \t"); s != ""; s = strings.TrimLeft(s, " \t") {
+		var word string
+		word, s = splitWord(s)
+		words = append(words, word)
+	}
+
+	return words
+}
@@ -0,0 +1,50 @@
+package popple
+
+import "testing"
+
+func TestParseCommand(t *testing.T) {
+	t.Run("splits name from rest", func(t *testing.T) {
+		cmd := parseCommand("karma foo bar")
+		if cmd.Name != "karma" {
+			t.Errorf("got name %q, want %q", cmd.Name, "karma")
+		}
+		if cmd.Rest != " foo bar" {
+			t.Errorf("got rest %q, want %q", cmd.Rest, " foo bar")
+		}
+	})
+
+	t.Run("strips quotes from the name", func(t *testing.T) {
+		cmd := parseCommand(`"karma" foo`)
+		if cmd.Name != "karma" {
+			t.Errorf("got name %q, want %q", cmd.Name, "karma")
+		}
+	})
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single word", "foo", []string{"foo"}},
+		{"several words", "foo bar baz", []string{"foo", "bar", "baz"}},
+		{"a quoted span stays together", `"kernel panic" foo`, []string{"kernel panic", "foo"}},
+		{"extra whitespace between words is tolerated", "foo   bar", []string{"foo", "bar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitWords(tt.in)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
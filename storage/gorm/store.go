@@ -0,0 +1,150 @@
+// Package gorm adapts Popple's core.Store interface onto a gorm.DB,
+// backing Popple with any SQL database gorm supports.
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/connorkuehl/popple/core"
+)
+
+// Entity is the gorm row for a core.Entity.
+type Entity struct {
+	gorm.Model
+	GuildID string `gorm:"index"`
+	Name    string `gorm:"index"`
+	Karma   int
+}
+
+// Config is the gorm row for a core.Config.
+type Config struct {
+	gorm.Model
+	GuildID    string `gorm:"uniqueIndex"`
+	NoAnnounce bool
+}
+
+// Ban is the gorm row for a core.Ban.
+type Ban struct {
+	gorm.Model
+	GuildID   string `gorm:"index"`
+	Target    string `gorm:"index"`
+	ExpiresAt *time.Time
+}
+
+// Store implements core.Store against a gorm.DB.
+type Store struct {
+	db *gorm.DB
+}
+
+// New returns a Store backed by db. db must already have Entity, Config
+// and Ban migrated, e.g. via db.AutoMigrate(&Entity{}, &Config{}, &Ban{}).
+func New(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetEntity(ctx context.Context, guildID, name string) (core.Entity, error) {
+	var e Entity
+	err := s.db.WithContext(ctx).Where(&Entity{GuildID: guildID, Name: name}).First(&e).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return core.Entity{}, err
+	}
+
+	return core.Entity{GuildID: guildID, Name: name, Karma: e.Karma}, nil
+}
+
+func (s *Store) SaveEntity(ctx context.Context, e core.Entity) error {
+	var row Entity
+	err := s.db.WithContext(ctx).Where(&Entity{GuildID: e.GuildID, Name: e.Name}).FirstOrInit(&row).Error
+	if err != nil {
+		return err
+	}
+	row.GuildID = e.GuildID
+	row.Name = e.Name
+	row.Karma = e.Karma
+
+	return s.db.WithContext(ctx).Save(&row).Error
+}
+
+func (s *Store) DeleteEntity(ctx context.Context, e core.Entity) error {
+	return s.db.WithContext(ctx).Where(&Entity{GuildID: e.GuildID, Name: e.Name}).Delete(&Entity{}).Error
+}
+
+func (s *Store) Leaderboard(ctx context.Context, guildID string, limit int) ([]core.Entity, error) {
+	return s.board(ctx, guildID, limit, "karma desc")
+}
+
+func (s *Store) Loserboard(ctx context.Context, guildID string, limit int) ([]core.Entity, error) {
+	return s.board(ctx, guildID, limit, "karma asc")
+}
+
+func (s *Store) board(ctx context.Context, guildID string, limit int, order string) ([]core.Entity, error) {
+	var rows []Entity
+	err := s.db.WithContext(ctx).Where(&Entity{GuildID: guildID}).Order(order).Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]core.Entity, 0, len(rows))
+	for _, r := range rows {
+		entities = append(entities, core.Entity{GuildID: r.GuildID, Name: r.Name, Karma: r.Karma})
+	}
+
+	return entities, nil
+}
+
+func (s *Store) Config(ctx context.Context, guildID string) (core.Config, error) {
+	var cfg Config
+	err := s.db.WithContext(ctx).Where(&Config{GuildID: guildID}).FirstOrCreate(&cfg).Error
+	if err != nil {
+		return core.Config{}, err
+	}
+
+	return core.Config{GuildID: cfg.GuildID, NoAnnounce: cfg.NoAnnounce}, nil
+}
+
+func (s *Store) SaveBan(ctx context.Context, b core.Ban) error {
+	var row Ban
+	err := s.db.WithContext(ctx).Where(&Ban{GuildID: b.GuildID, Target: b.Target}).FirstOrInit(&row).Error
+	if err != nil {
+		return err
+	}
+	row.GuildID = b.GuildID
+	row.Target = b.Target
+	row.ExpiresAt = b.ExpiresAt
+
+	return s.db.WithContext(ctx).Save(&row).Error
+}
+
+func (s *Store) DeleteBan(ctx context.Context, guildID, target string) error {
+	return s.db.WithContext(ctx).Where(&Ban{GuildID: guildID, Target: target}).Delete(&Ban{}).Error
+}
+
+func (s *Store) Bans(ctx context.Context, guildID string) ([]core.Ban, error) {
+	var rows []Ban
+	err := s.db.WithContext(ctx).Where(&Ban{GuildID: guildID}).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]core.Ban, 0, len(rows))
+	for _, r := range rows {
+		bans = append(bans, core.Ban{GuildID: r.GuildID, Target: r.Target, ExpiresAt: r.ExpiresAt})
+	}
+
+	return bans, nil
+}
+
+func (s *Store) SaveConfig(ctx context.Context, cfg core.Config) error {
+	var row Config
+	err := s.db.WithContext(ctx).Where(&Config{GuildID: cfg.GuildID}).FirstOrInit(&row).Error
+	if err != nil {
+		return err
+	}
+	row.GuildID = cfg.GuildID
+	row.NoAnnounce = cfg.NoAnnounce
+
+	return s.db.WithContext(ctx).Save(&row).Error
+}
@@ -0,0 +1,135 @@
+// Package memory provides an in-memory core.Store for tests and for
+// single-binary deployments that don't need persistence across restarts.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/connorkuehl/popple/core"
+)
+
+type entityKey struct {
+	guildID string
+	name    string
+}
+
+// Store is a core.Store backed by plain Go maps. It is safe for
+// concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	entities map[entityKey]core.Entity
+	configs  map[string]core.Config
+	bans     map[entityKey]core.Ban
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		entities: make(map[entityKey]core.Entity),
+		configs:  make(map[string]core.Config),
+		bans:     make(map[entityKey]core.Ban),
+	}
+}
+
+func (s *Store) GetEntity(ctx context.Context, guildID, name string) (core.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entities[entityKey{guildID, name}]
+	if !ok {
+		return core.Entity{GuildID: guildID, Name: name}, nil
+	}
+
+	return e, nil
+}
+
+func (s *Store) SaveEntity(ctx context.Context, e core.Entity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entities[entityKey{e.GuildID, e.Name}] = e
+	return nil
+}
+
+func (s *Store) DeleteEntity(ctx context.Context, e core.Entity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entities, entityKey{e.GuildID, e.Name})
+	return nil
+}
+
+func (s *Store) Leaderboard(ctx context.Context, guildID string, limit int) ([]core.Entity, error) {
+	return s.board(guildID, limit, func(a, b core.Entity) bool { return a.Karma > b.Karma })
+}
+
+func (s *Store) Loserboard(ctx context.Context, guildID string, limit int) ([]core.Entity, error) {
+	return s.board(guildID, limit, func(a, b core.Entity) bool { return a.Karma < b.Karma })
+}
+
+func (s *Store) board(guildID string, limit int, less func(a, b core.Entity) bool) ([]core.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entities []core.Entity
+	for _, e := range s.entities {
+		if e.GuildID == guildID {
+			entities = append(entities, e)
+		}
+	}
+
+	sort.Slice(entities, func(i, j int) bool { return less(entities[i], entities[j]) })
+
+	if limit < len(entities) {
+		entities = entities[:limit]
+	}
+
+	return entities, nil
+}
+
+func (s *Store) Config(ctx context.Context, guildID string) (core.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.configs[guildID], nil
+}
+
+func (s *Store) SaveConfig(ctx context.Context, cfg core.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configs[cfg.GuildID] = cfg
+	return nil
+}
+
+func (s *Store) SaveBan(ctx context.Context, b core.Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bans[entityKey{b.GuildID, b.Target}] = b
+	return nil
+}
+
+func (s *Store) DeleteBan(ctx context.Context, guildID, target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.bans, entityKey{guildID, target})
+	return nil
+}
+
+func (s *Store) Bans(ctx context.Context, guildID string) ([]core.Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bans []core.Ban
+	for k, b := range s.bans {
+		if k.guildID == guildID {
+			bans = append(bans, b)
+		}
+	}
+
+	return bans, nil
+}
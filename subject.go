@@ -0,0 +1,52 @@
+package popple
+
+import "regexp"
+
+// Subject is a single karma increment or decrement parsed out of a
+// message, e.g. "foo++" yields Subject{Name: "foo", Karma: 1}.
+type Subject struct {
+	Name  string
+	Karma int
+}
+
+var subjectPattern = regexp.MustCompile(`("[^"]+"|\pL[\w-]*|@\pL[\w-]*)(\+\+|--)`)
+
+// ParseSubjects scans message for karma increment/decrement tokens and
+// returns one Subject per match, in the order they appear. A subject
+// name may be double-quoted to admit whitespace, e.g. `"kernel
+// panic"++`.
+func ParseSubjects(message string) []Subject {
+	matches := subjectPattern.FindAllStringSubmatch(message, -1)
+
+	subjects := make([]Subject, 0, len(matches))
+	for _, match := range matches {
+		karma := 1
+		if match[2] == "--" {
+			karma = -1
+		}
+
+		name := match[1]
+		if len(name) >= 2 && name[0] == '"' {
+			name = name[1 : len(name)-1]
+		}
+
+		subjects = append(subjects, Subject{Name: name, Karma: karma})
+	}
+
+	return subjects
+}
+
+// MarshalSubjects collapses a list of Subjects into a map of name to net
+// karma change, folding "@user" mentions into the bare "user" name.
+func MarshalSubjects(subjects []Subject) map[string]int {
+	subMap := make(map[string]int)
+	for _, s := range subjects {
+		name := s.Name
+		if len(name) > 1 && name[0] == '@' {
+			name = name[1:]
+		}
+		subMap[name] += s.Karma
+	}
+
+	return subMap
+}